@@ -3,10 +3,14 @@ package main
 import (
 	"log"
 
+	plog "github.com/thomhuang/proglog/internal/log"
 	"github.com/thomhuang/proglog/internal/server"
 )
 
 func main() {
-	srv := server.NewHTTPServer(":8080")
+	srv, err := server.NewHTTPServer(":8080", plog.Config{})
+	if err != nil {
+		log.Fatal(err)
+	}
 	log.Fatal(srv.ListenAndServe())
 }
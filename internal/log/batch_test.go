@@ -0,0 +1,61 @@
+package log
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogAppendBatch(t *testing.T) {
+	dir, err := os.MkdirTemp("", "batch_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := Config{}
+	c.Sync.Mode = SyncBatch
+	l, err := NewLog(dir, c)
+	require.NoError(t, err)
+	defer l.Close()
+
+	offsets, err := l.AppendBatch([][]byte{
+		[]byte("one"),
+		[]byte("two"),
+		[]byte("three"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0, 1, 2}, offsets)
+
+	for i, want := range []string{"one", "two", "three"} {
+		got, err := l.Read(uint64(i))
+		require.NoError(t, err)
+		require.Equal(t, want, string(got))
+	}
+}
+
+// TestLogAppendBatchEmpty guards against a panic in the segment-roll
+// check when the active segment is already maxed and the batch is
+// empty - reachable in practice after a crash that left a maxed segment
+// active for setup() to reopen.
+func TestLogAppendBatchEmpty(t *testing.T) {
+	dir, err := os.MkdirTemp("", "batch_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := Config{}
+	c.Segment.MaxStoreBytes = 1
+	l, err := NewLog(dir, c)
+	require.NoError(t, err)
+	defer l.Close()
+
+	// write directly to the segment, bypassing Log.Append's own roll
+	// check, to land in the state setup() would reopen after a crash: an
+	// active segment that's already over its max size
+	_, err = l.activeSegment.Append([]byte("x"))
+	require.NoError(t, err)
+	require.True(t, l.activeSegment.IsMaxed())
+
+	offsets, err := l.AppendBatch(nil)
+	require.NoError(t, err)
+	require.Nil(t, offsets)
+}
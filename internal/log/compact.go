@@ -0,0 +1,135 @@
+package log
+
+import (
+	"fmt"
+	"os"
+
+	art "github.com/plar/go-adaptive-radix-tree"
+)
+
+// Compact rewrites every live key's record into a fresh set of segments
+// and drops everything else: tombstoned keys, and any earlier copy of a
+// key that's since been overwritten. It requires Config.KV.Enabled, and
+// blocks all reads and writes for its duration.
+func (l *Log) Compact() error {
+	if l.kv == nil {
+		return fmt.Errorf("log: KV overlay not enabled")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	type liveRecord struct {
+		key   []byte
+		value []byte
+	}
+
+	var live []liveRecord
+	l.kvMu.RLock()
+	l.kv.ForEach(func(node art.Node) bool {
+		entry := node.Value().(kvEntry)
+
+		var seg *segment
+		for _, s := range l.segments {
+			if s.baseOffset == entry.segmentBaseOffset {
+				seg = s
+				break
+			}
+		}
+		if seg == nil {
+			// the segment behind this entry is already gone (pruned by
+			// retention); there's nothing left to carry forward
+			return true
+		}
+
+		raw, err := seg.store.Read(entry.storePosition)
+		if err != nil {
+			return true
+		}
+		_, value, err := decodeKV(raw)
+		if err != nil {
+			return true
+		}
+
+		key := make([]byte, len(node.Key()))
+		copy(key, node.Key())
+		val := make([]byte, len(value))
+		copy(val, value)
+		live = append(live, liveRecord{key: key, value: val})
+		return true
+	})
+	l.kvMu.RUnlock()
+
+	newDir := l.Dir + ".compacting"
+	if err := os.RemoveAll(newDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(newDir, 0755); err != nil {
+		return err
+	}
+
+	rewritten := &Log{Dir: newDir, Config: l.Config}
+	if err := rewritten.newSegment(l.Config.Segment.InitialOffset); err != nil {
+		return err
+	}
+
+	newIndex := art.New()
+	for _, rec := range live {
+		raw, err := encodeKV(rec.key, rec.value)
+		if err != nil {
+			return err
+		}
+		off, err := rewritten.activeSegment.Append(raw)
+		if err != nil {
+			return err
+		}
+		if rewritten.activeSegment.IsMaxed() {
+			if err := rewritten.newSegment(off + 1); err != nil {
+				return err
+			}
+		}
+
+		seg, pos, err := rewritten.locate(off)
+		if err != nil {
+			return err
+		}
+		newIndex.Insert(art.Key(rec.key), kvEntry{
+			segmentBaseOffset: seg.baseOffset,
+			storePosition:     pos,
+		})
+	}
+
+	// flush and close the rewritten segments so their contents are on
+	// disk before we swap them in
+	for _, s := range rewritten.segments {
+		if err := s.Close(); err != nil {
+			return err
+		}
+	}
+
+	oldSegments := l.segments
+	oldDir := l.Dir
+	for _, s := range oldSegments {
+		if err := s.Close(); err != nil {
+			return err
+		}
+	}
+	if err := os.RemoveAll(oldDir); err != nil {
+		return err
+	}
+	if err := os.Rename(newDir, oldDir); err != nil {
+		return err
+	}
+
+	l.segments = nil
+	l.activeSegment = nil
+	if err := l.setup(); err != nil {
+		return err
+	}
+
+	l.kvMu.Lock()
+	l.kv = newIndex
+	l.kvMu.Unlock()
+
+	return nil
+}
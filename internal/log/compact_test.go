@@ -0,0 +1,37 @@
+package log
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogCompact(t *testing.T) {
+	dir, err := os.MkdirTemp("", "compact_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := Config{}
+	c.KV.Enabled = true
+	l, err := NewLog(dir, c)
+	require.NoError(t, err)
+	defer l.Close()
+
+	_, err = l.AppendKV([]byte("a"), []byte("1"))
+	require.NoError(t, err)
+	_, err = l.AppendKV([]byte("a"), []byte("2")) // shadows the first
+	require.NoError(t, err)
+	_, err = l.AppendKV([]byte("b"), []byte("3"))
+	require.NoError(t, err)
+	require.NoError(t, l.Delete([]byte("b")))
+
+	require.NoError(t, l.Compact())
+
+	got, err := l.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, "2", string(got))
+
+	_, err = l.Get([]byte("b"))
+	require.Equal(t, ErrKeyNotFound, err)
+}
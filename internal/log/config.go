@@ -0,0 +1,47 @@
+package log
+
+import "time"
+
+// Config configures how a Log's segments behave on disk: how large each
+// segment's store and index files are allowed to grow before the log
+// rolls a new one, where a brand-new log should start numbering offsets
+// from, and how long it retains data once it has more than one segment.
+type Config struct {
+	Segment struct {
+		MaxStoreBytes uint64
+		MaxIndexBytes uint64
+		InitialOffset uint64
+	}
+
+	// Retention bounds how much of the log is kept around once it spans
+	// more than one segment. A zero value disables that bound. The
+	// active segment is never pruned, no matter how it compares.
+	Retention struct {
+		MaxBytes uint64
+		MaxAge   time.Duration
+
+		// CheckInterval is how often the retention goroutine scans for
+		// segments to prune. Defaults to defaultRetentionCheckInterval
+		// if left zero. Set this before the log is opened - the
+		// goroutine reads it once, at startup, rather than on every
+		// tick, so changing it afterward has no effect.
+		CheckInterval time.Duration
+	}
+
+	// Metrics receives storage and retention events as they happen. It
+	// defaults to a no-op hook if left nil.
+	Metrics MetricsHook
+
+	// Sync controls when the active segment is flushed to stable
+	// storage. Defaults to SyncNever.
+	Sync SyncConfig
+
+	// KV turns the log into a bitcask-style keyed store on top of its
+	// offset-addressed records. Every record appended to a log with KV
+	// enabled must go through AppendKV/Delete, not the plain Append -
+	// the KV overlay can't tell a keyed record apart from an arbitrary
+	// byte slice.
+	KV struct {
+		Enabled bool
+	}
+}
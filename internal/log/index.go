@@ -1,6 +1,7 @@
 package log
 
 import (
+	"fmt"
 	"io"
 	"os"
 
@@ -118,3 +119,33 @@ func (i *index) Write(off uint32, pos uint64) error {
 func (i *index) Name() string {
 	return i.file.Name()
 }
+
+// WriteBatch writes many (offset, position) entries in one pass, same as
+// calling Write for each pair but without re-checking bounds between
+// entries.
+func (i *index) WriteBatch(offs []uint32, positions []uint64) error {
+	if len(offs) != len(positions) {
+		return fmt.Errorf("log: offs and positions must be the same length")
+	}
+
+	need := uint64(len(offs)) * totWidth
+	if uint64(len(i.mmap)) < i.size+need {
+		return io.EOF
+	}
+
+	for j := range offs {
+		enc.PutUint32(i.mmap[i.size:i.size+offWidth], offs[j])
+		enc.PutUint64(i.mmap[i.size+offWidth:i.size+totWidth], positions[j])
+		i.size += totWidth
+	}
+	return nil
+}
+
+// Sync flushes the memory-mapped index to its backing file and flushes
+// that file to stable storage, without truncating it the way Close does.
+func (i *index) Sync() error {
+	if err := i.mmap.Sync(gommap.MS_SYNC); err != nil {
+		return err
+	}
+	return i.file.Sync()
+}
@@ -0,0 +1,256 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+
+	art "github.com/plar/go-adaptive-radix-tree"
+)
+
+// ErrKeyNotFound is returned by Log.Get when no live record matches a
+// key.
+var ErrKeyNotFound = fmt.Errorf("key not found")
+
+// errMalformedKVRecord is returned by decodeKV when b is too short to
+// hold its 2-byte key-length prefix, or that prefix claims a key longer
+// than the record itself. A log's own encodeKV never produces such a
+// record; seeing one means something appended raw bytes to a KV-enabled
+// log without going through AppendKV/Delete.
+var errMalformedKVRecord = fmt.Errorf("log: malformed KV record")
+
+// maxKVKeyLen is the largest key encodeKV can pack into its 2-byte
+// length prefix.
+const maxKVKeyLen = 1<<16 - 1
+
+// ErrKeyTooLong is returned by AppendKV/Delete when key is longer than
+// maxKVKeyLen - encodeKV has no room to record its true length, and
+// silently wrapping it would split the stored bytes at the wrong
+// boundary on every read thereafter.
+var ErrKeyTooLong = fmt.Errorf("log: key longer than %d bytes", maxKVKeyLen)
+
+// tombstoneValue marks a key as deleted. It's appended as an ordinary
+// record, the same as any other value, so a tombstone replays correctly
+// when the log's KV index is rebuilt on restart.
+var tombstoneValue = []byte{0}
+
+// kvEntry locates a keyed record on disk without re-reading the whole
+// log: which segment it lives in, by baseOffset, and where inside that
+// segment's store.
+type kvEntry struct {
+	segmentBaseOffset uint64
+	storePosition     uint64
+	size              uint64
+}
+
+// encodeKV packs key and value into the single byte slice a segment's
+// store actually holds: a 2-byte key length, the key, then the value.
+func encodeKV(key, value []byte) ([]byte, error) {
+	if len(key) > maxKVKeyLen {
+		return nil, ErrKeyTooLong
+	}
+
+	b := make([]byte, 2+len(key)+len(value))
+	enc.PutUint16(b[:2], uint16(len(key)))
+	copy(b[2:2+len(key)], key)
+	copy(b[2+len(key):], value)
+	return b, nil
+}
+
+func decodeKV(b []byte) (key, value []byte, err error) {
+	if len(b) < 2 {
+		return nil, nil, errMalformedKVRecord
+	}
+	keyLen := enc.Uint16(b[:2])
+	if int(keyLen) > len(b)-2 {
+		return nil, nil, errMalformedKVRecord
+	}
+	return b[2 : 2+keyLen], b[2+keyLen:], nil
+}
+
+// rebuildKV scans every segment's index and store, in offset order, and
+// replays each record into the in-memory ART index. Called once, from
+// NewLog, when Config.KV.Enabled.
+func (l *Log) rebuildKV() error {
+	l.kv = art.New()
+
+	for _, s := range l.segments {
+		entries := s.index.size / totWidth
+		for rel := uint64(0); rel < entries; rel++ {
+			_, pos, err := s.index.Read(int64(rel))
+			if err != nil {
+				return err
+			}
+
+			raw, err := s.store.Read(pos)
+			if err != nil {
+				return err
+			}
+
+			key, value, err := decodeKV(raw)
+			if err != nil {
+				return fmt.Errorf("log: rebuilding KV index at offset %d: %w", s.baseOffset+rel, err)
+			}
+			if bytes.Equal(value, tombstoneValue) {
+				l.kv.Delete(art.Key(key))
+				continue
+			}
+			l.kv.Insert(art.Key(key), kvEntry{
+				segmentBaseOffset: s.baseOffset,
+				storePosition:     pos,
+				size:              uint64(len(raw)),
+			})
+		}
+	}
+	return nil
+}
+
+// AppendKV appends a keyed record and overwrites (or creates) that key's
+// entry in the in-memory index, returning the offset assigned to it.
+// kvAppendMu holds the append and the index update together as one step,
+// so two concurrent AppendKVs can't have their Insert calls land in the
+// opposite order from the offsets they were assigned - which would leave
+// the index pointing at the older value instead of the last write.
+func (l *Log) AppendKV(key, value []byte) (uint64, error) {
+	if l.kv == nil {
+		return 0, fmt.Errorf("log: KV overlay not enabled")
+	}
+
+	l.kvAppendMu.Lock()
+	defer l.kvAppendMu.Unlock()
+
+	raw, err := encodeKV(key, value)
+	if err != nil {
+		return 0, err
+	}
+
+	off, err := l.Append(raw)
+	if err != nil {
+		return 0, err
+	}
+
+	seg, pos, err := l.locate(off)
+	if err != nil {
+		return 0, err
+	}
+
+	l.kvMu.Lock()
+	l.kv.Insert(art.Key(key), kvEntry{
+		segmentBaseOffset: seg.baseOffset,
+		storePosition:     pos,
+		size:              uint64(len(raw)),
+	})
+	l.kvMu.Unlock()
+
+	return off, nil
+}
+
+// Get returns the live value stored for key.
+func (l *Log) Get(key []byte) ([]byte, error) {
+	if l.kv == nil {
+		return nil, fmt.Errorf("log: KV overlay not enabled")
+	}
+
+	l.kvMu.RLock()
+	v, found := l.kv.Search(art.Key(key))
+	l.kvMu.RUnlock()
+	if !found {
+		return nil, ErrKeyNotFound
+	}
+
+	entry := v.(kvEntry)
+	seg, err := l.segmentByBaseOffset(entry.segmentBaseOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := seg.store.Read(entry.storePosition)
+	if err != nil {
+		return nil, err
+	}
+	_, value, err := decodeKV(raw)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Delete writes a tombstone record for key and removes it from the
+// in-memory index; the underlying store entries it once pointed at are
+// reclaimed later, by compaction. It shares kvAppendMu with AppendKV so
+// a concurrent write to the same key can't race its index update.
+func (l *Log) Delete(key []byte) error {
+	if l.kv == nil {
+		return fmt.Errorf("log: KV overlay not enabled")
+	}
+
+	l.kvAppendMu.Lock()
+	defer l.kvAppendMu.Unlock()
+
+	raw, err := encodeKV(key, tombstoneValue)
+	if err != nil {
+		return err
+	}
+	if _, err := l.Append(raw); err != nil {
+		return err
+	}
+
+	l.kvMu.Lock()
+	l.kv.Delete(art.Key(key))
+	l.kvMu.Unlock()
+	return nil
+}
+
+// Keys returns every live key with the given prefix. A nil or empty
+// prefix returns every live key.
+func (l *Log) Keys(prefix []byte) [][]byte {
+	if l.kv == nil {
+		return nil
+	}
+
+	l.kvMu.RLock()
+	defer l.kvMu.RUnlock()
+
+	var keys [][]byte
+	l.kv.ForEachPrefix(art.Key(prefix), func(node art.Node) bool {
+		k := make([]byte, len(node.Key()))
+		copy(k, node.Key())
+		keys = append(keys, k)
+		return true
+	})
+	return keys
+}
+
+// locate finds the segment and store position a given offset's record
+// was written to, so AppendKV can index it without re-reading the value
+// back out.
+func (l *Log) locate(off uint64) (*segment, uint64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, s := range l.segments {
+		if s.baseOffset <= off && off < s.nextOffset {
+			_, pos, err := s.index.Read(int64(off - s.baseOffset))
+			if err != nil {
+				return nil, 0, err
+			}
+			return s, pos, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("offset out of range: %d", off)
+}
+
+// segmentByBaseOffset looks up a still-live segment by its base offset.
+// A kvEntry can outlive its segment if retention prunes it first; Get
+// surfaces that as a plain read error rather than silently returning
+// stale data.
+func (l *Log) segmentByBaseOffset(base uint64) (*segment, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, s := range l.segments {
+		if s.baseOffset == base {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("log: segment with base offset %d is gone", base)
+}
@@ -0,0 +1,146 @@
+package log
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogKV(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kv_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := Config{}
+	c.KV.Enabled = true
+	l, err := NewLog(dir, c)
+	require.NoError(t, err)
+	defer l.Close()
+
+	_, err = l.AppendKV([]byte("user:1"), []byte("alice"))
+	require.NoError(t, err)
+	_, err = l.AppendKV([]byte("user:2"), []byte("bob"))
+	require.NoError(t, err)
+
+	got, err := l.Get([]byte("user:1"))
+	require.NoError(t, err)
+	require.Equal(t, "alice", string(got))
+
+	// overwriting a key should shadow the earlier value
+	_, err = l.AppendKV([]byte("user:1"), []byte("alicia"))
+	require.NoError(t, err)
+	got, err = l.Get([]byte("user:1"))
+	require.NoError(t, err)
+	require.Equal(t, "alicia", string(got))
+
+	keys := l.Keys([]byte("user:"))
+	require.Len(t, keys, 2)
+
+	require.NoError(t, l.Delete([]byte("user:2")))
+	_, err = l.Get([]byte("user:2"))
+	require.Equal(t, ErrKeyNotFound, err)
+
+	require.NoError(t, l.Close())
+
+	// restarting should rebuild the index from what's on disk
+	l, err = NewLog(dir, c)
+	require.NoError(t, err)
+	defer l.Close()
+
+	got, err = l.Get([]byte("user:1"))
+	require.NoError(t, err)
+	require.Equal(t, "alicia", string(got))
+
+	_, err = l.Get([]byte("user:2"))
+	require.Equal(t, ErrKeyNotFound, err)
+}
+
+// TestLogKVRebuildRejectsPlainRecord guards against rebuildKV panicking
+// on a record that was never encodeKV'd - reachable in practice if a
+// caller appends through the plain (non-KV) path against a KV-enabled
+// log, which decodeKV used to trust blindly as a 2-byte length prefix.
+func TestLogKVRebuildRejectsPlainRecord(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kv_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := Config{}
+	c.KV.Enabled = true
+	l, err := NewLog(dir, c)
+	require.NoError(t, err)
+
+	_, err = l.Append([]byte("hello world"))
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	_, err = NewLog(dir, c)
+	require.Error(t, err)
+}
+
+// TestLogKVRejectsOversizedKey guards against a key longer than
+// encodeKV's 2-byte length prefix can hold silently wrapping around and
+// getting split at the wrong boundary on every later decode.
+func TestLogKVRejectsOversizedKey(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kv_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := Config{}
+	c.KV.Enabled = true
+	l, err := NewLog(dir, c)
+	require.NoError(t, err)
+	defer l.Close()
+
+	oversized := make([]byte, maxKVKeyLen+1)
+
+	_, err = l.AppendKV(oversized, []byte("v"))
+	require.Equal(t, ErrKeyTooLong, err)
+
+	require.Equal(t, ErrKeyTooLong, l.Delete(oversized))
+}
+
+// TestLogKVConcurrentAppend guards against the index update landing out
+// of offset order: whichever write actually gets the highest offset
+// must be the one Get sees afterward, no matter how the goroutines were
+// scheduled.
+func TestLogKVConcurrentAppend(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kv_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := Config{}
+	c.KV.Enabled = true
+	l, err := NewLog(dir, c)
+	require.NoError(t, err)
+	defer l.Close()
+
+	const writes = 50
+	offsets := make([]uint64, writes)
+
+	var wg sync.WaitGroup
+	for i := 0; i < writes; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			off, err := l.AppendKV([]byte("k"), []byte{byte(i)})
+			require.NoError(t, err)
+			offsets[i] = off
+		}(i)
+	}
+	wg.Wait()
+
+	var want byte
+	highest := uint64(0)
+	for i, off := range offsets {
+		if off >= highest {
+			highest = off
+			want = byte(i)
+		}
+	}
+
+	got, err := l.Get([]byte("k"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{want}, got)
+}
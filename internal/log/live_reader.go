@@ -0,0 +1,146 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// closedSegmentPollInterval bounds how long LiveReader waits on a
+// segment that's no longer active before re-checking whether the
+// record it wants has rolled into the next one. A closed segment's
+// store will never grow again, so a plain store.Wait would block
+// forever once the reader has caught up to it.
+const closedSegmentPollInterval = 50 * time.Millisecond
+
+// LiveReader reads a store as it grows: Next returns the next
+// length-prefixed record, blocking until one is written (or ctx is
+// done) if the reader has caught up to the write head.
+type LiveReader struct {
+	s   *store
+	pos uint64
+}
+
+// NewLiveReader returns a LiveReader positioned at the start of s.
+func (s *store) NewLiveReader() *LiveReader {
+	return &LiveReader{s: s}
+}
+
+// Next returns the next record, blocking on ctx until it's available.
+func (r *LiveReader) Next(ctx context.Context) ([]byte, error) {
+	if err := r.s.Wait(ctx, r.pos+lenWidth); err != nil {
+		return nil, err
+	}
+
+	size := make([]byte, lenWidth)
+	if _, err := r.s.ReadAt(size, int64(r.pos)); err != nil {
+		return nil, err
+	}
+	recLen := enc.Uint64(size)
+
+	// a writer can flush the length header before it's flushed the
+	// payload that follows it, so wait for the whole record to land
+	// before decoding it
+	if err := r.s.Wait(ctx, r.pos+lenWidth+recLen); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, recLen)
+	if _, err := r.s.ReadAt(b, int64(r.pos+lenWidth)); err != nil {
+		return nil, err
+	}
+
+	r.pos += lenWidth + recLen
+	return b, nil
+}
+
+// LogLiveReader follows a Log as it grows, transparently crossing
+// segment boundaries as the log rolls.
+type LogLiveReader struct {
+	l   *Log
+	off uint64
+
+	seg    *segment
+	reader *LiveReader
+}
+
+// NewLiveReader returns a LogLiveReader that starts at from and follows
+// the log from there on.
+func (l *Log) NewLiveReader(from uint64) *LogLiveReader {
+	return &LogLiveReader{l: l, off: from}
+}
+
+// Next returns the record at the reader's current offset, blocking
+// until it's appended if the reader has caught up to the log's write
+// head, and returns once ctx is done.
+func (r *LogLiveReader) Next(ctx context.Context) ([]byte, error) {
+	for {
+		s, pos, active, err := r.resolve()
+		if err != nil {
+			return nil, err
+		}
+
+		if r.seg != s {
+			r.seg = s
+			r.reader = s.store.NewLiveReader()
+			r.reader.pos = pos
+		}
+
+		waitCtx := ctx
+		var cancel context.CancelFunc
+		if !active {
+			// this segment is closed and will never grow again; give up
+			// after a short wait so we come back around and notice the
+			// record we want has rolled into the next segment
+			waitCtx, cancel = context.WithTimeout(ctx, closedSegmentPollInterval)
+		}
+
+		b, err := r.reader.Next(waitCtx)
+		if cancel != nil {
+			cancel()
+		}
+		if err == nil {
+			r.off++
+			return b, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		// timed out waiting on a now-closed segment; loop and re-resolve
+	}
+}
+
+// resolve finds the segment that owns r.off (or will, if it's the next
+// record to be written to the active segment) and the store position to
+// start reading from.
+func (r *LogLiveReader) resolve() (s *segment, pos uint64, active bool, err error) {
+	r.l.mu.RLock()
+	defer r.l.mu.RUnlock()
+
+	for _, seg := range r.l.segments {
+		if seg.baseOffset <= r.off {
+			s = seg
+		} else {
+			break
+		}
+	}
+	if s == nil {
+		return nil, 0, false, fmt.Errorf("%w: %d", ErrOffsetOutOfRange, r.off)
+	}
+
+	if r.off < s.nextOffset {
+		_, p, readErr := s.index.Read(int64(r.off - s.baseOffset))
+		if readErr != nil {
+			return nil, 0, false, readErr
+		}
+		pos = p
+	} else {
+		pos = s.store.size
+	}
+
+	return s, pos, s == r.l.activeSegment, nil
+}
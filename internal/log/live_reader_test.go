@@ -0,0 +1,84 @@
+package log
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogLiveReader(t *testing.T) {
+	dir, err := os.MkdirTemp("", "live_reader_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := Config{}
+	c.Segment.MaxStoreBytes = 1024
+	l, err := NewLog(dir, c)
+	require.NoError(t, err)
+	defer l.Close()
+
+	_, err = l.Append([]byte("first"))
+	require.NoError(t, err)
+
+	reader := l.NewLiveReader(0)
+
+	got, err := reader.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []byte("first"), got)
+
+	// the reader has caught up, so the next call should block until we
+	// append another record
+	resultCh := make(chan []byte, 1)
+	go func() {
+		b, err := reader.Next(context.Background())
+		require.NoError(t, err)
+		resultCh <- b
+	}()
+
+	select {
+	case <-resultCh:
+		t.Fatal("Next returned before a record was appended")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	_, err = l.Append([]byte("second"))
+	require.NoError(t, err)
+
+	select {
+	case got := <-resultCh:
+		require.Equal(t, []byte("second"), got)
+	case <-time.After(time.Second):
+		t.Fatal("Next didn't wake up after the append")
+	}
+}
+
+func TestLogLiveReaderContextCancel(t *testing.T) {
+	dir, err := os.MkdirTemp("", "live_reader_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := NewLog(dir, Config{})
+	require.NoError(t, err)
+	defer l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := l.NewLiveReader(0)
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := reader.Next(ctx)
+		errCh <- err
+	}()
+
+	cancel()
+
+	select {
+	case err := <-errCh:
+		require.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Next didn't return after ctx was canceled")
+	}
+}
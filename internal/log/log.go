@@ -0,0 +1,282 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	art "github.com/plar/go-adaptive-radix-tree"
+)
+
+// defaultRetentionCheckInterval is how often a Log with retention
+// configured scans its segments for pruning.
+const defaultRetentionCheckInterval = time.Minute
+
+// ErrOffsetOutOfRange is returned by Log.Read when off isn't covered by
+// any segment the log currently holds - as opposed to any other error
+// Read can return, which means an underlying read actually failed.
+var ErrOffsetOutOfRange = fmt.Errorf("offset out of range")
+
+// Log is an ordered, append-only sequence of records backed by a
+// directory of segments on disk. Writes always land on the newest
+// (active) segment; once that segment is maxed out per Config.Segment,
+// the log rolls a new one.
+type Log struct {
+	mu sync.RWMutex
+
+	Dir    string
+	Config Config
+
+	activeSegment *segment
+	segments      []*segment
+
+	retentionDone chan struct{}
+
+	syncDone chan struct{}
+
+	// closeOnce guards retentionDone/syncDone so Close can be called more
+	// than once, and so it never races the background goroutines' reads
+	// of those fields with its own close+nil.
+	closeOnce sync.Once
+
+	// kv is the optional bitcask-style key index; nil unless
+	// Config.KV.Enabled. kvAppendMu serializes AppendKV/Delete's
+	// append-then-index-update sequence, so concurrent writers can't
+	// have their index updates land in a different order than the
+	// offsets they were assigned.
+	kvAppendMu sync.Mutex
+	kvMu       sync.RWMutex
+	kv         art.Tree
+}
+
+// NewLog opens the log rooted at dir, creating it if it doesn't already
+// have segments, and falls back to a modest default segment size if the
+// caller didn't set one.
+func NewLog(dir string, c Config) (*Log, error) {
+	if c.Segment.MaxStoreBytes == 0 {
+		c.Segment.MaxStoreBytes = 1024
+	}
+	if c.Segment.MaxIndexBytes == 0 {
+		c.Segment.MaxIndexBytes = 1024
+	}
+	if c.Metrics == nil {
+		c.Metrics = noopMetricsHook{}
+	}
+
+	l := &Log{
+		Dir:    dir,
+		Config: c,
+	}
+	if err := l.setup(); err != nil {
+		return nil, err
+	}
+	if c.KV.Enabled {
+		if err := l.rebuildKV(); err != nil {
+			return nil, err
+		}
+	}
+	l.startRetention()
+	l.startSync()
+	return l, nil
+}
+
+func (l *Log) setup() error {
+	files, err := os.ReadDir(l.Dir)
+	if err != nil {
+		return err
+	}
+
+	var baseOffsets []uint64
+	for _, file := range files {
+		ext := filepath.Ext(file.Name())
+		if ext != ".store" && ext != ".index" {
+			// not a segment file, e.g. the replication checkpoint
+			continue
+		}
+
+		offStr := strings.TrimSuffix(file.Name(), ext)
+		off, err := strconv.ParseUint(offStr, 10, 0)
+		if err != nil {
+			continue
+		}
+		baseOffsets = append(baseOffsets, off)
+	}
+	sort.Slice(baseOffsets, func(i, j int) bool {
+		return baseOffsets[i] < baseOffsets[j]
+	})
+
+	for i := 0; i < len(baseOffsets); i++ {
+		if err := l.newSegment(baseOffsets[i]); err != nil {
+			return err
+		}
+		// baseOffsets contains a duplicate for each segment's store and
+		// index file, so skip the one we just consumed
+		i++
+	}
+
+	if l.segments == nil {
+		if err := l.newSegment(l.Config.Segment.InitialOffset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *Log) newSegment(off uint64) error {
+	s, err := newSegment(l.Dir, off, l.Config)
+	if err != nil {
+		return err
+	}
+	l.segments = append(l.segments, s)
+	l.activeSegment = s
+	return nil
+}
+
+// Append writes p to the active segment, rolling a new segment first if
+// the active one is now maxed out, and returns the assigned offset.
+func (l *Log) Append(p []byte) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.appendLocked(p)
+}
+
+// AppendAt behaves like Append, except it fails - without writing
+// anything - if appending p would not assign it offset off. Log.Follow
+// uses this so a replicated record whose offset doesn't match what this
+// log would assign next is caught before it lands, rather than appended
+// under the wrong offset and only noticed afterward.
+func (l *Log) AppendAt(off uint64, p []byte) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if next := l.activeSegment.nextOffset; next != off {
+		return 0, fmt.Errorf("log: append offset mismatch: next is %d, wanted %d", next, off)
+	}
+	return l.appendLocked(p)
+}
+
+func (l *Log) appendLocked(p []byte) (uint64, error) {
+	off, err := l.activeSegment.Append(p)
+	if err != nil {
+		return 0, err
+	}
+
+	if l.Config.Sync.Mode == SyncAlways {
+		if err := l.activeSegment.Sync(); err != nil {
+			return off, err
+		}
+	}
+
+	if l.activeSegment.IsMaxed() {
+		err = l.newSegment(off + 1)
+	}
+	return off, err
+}
+
+// AppendBatch writes every record in ps to the active segment in one
+// pass and returns the offsets assigned to them, in order. Per
+// Config.Sync.Mode, it may flush the segment to stable storage once the
+// whole batch has landed.
+func (l *Log) AppendBatch(ps [][]byte) ([]uint64, error) {
+	if len(ps) == 0 {
+		return nil, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	offsets, err := l.activeSegment.AppendBatch(ps)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.Config.Sync.Mode == SyncAlways || l.Config.Sync.Mode == SyncBatch {
+		if err := l.activeSegment.Sync(); err != nil {
+			return offsets, err
+		}
+	}
+
+	if l.activeSegment.IsMaxed() {
+		if err := l.newSegment(offsets[len(offsets)-1] + 1); err != nil {
+			return offsets, err
+		}
+	}
+	return offsets, nil
+}
+
+// Read returns the record stored at the given offset.
+func (l *Log) Read(off uint64) ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var s *segment
+	for _, segment := range l.segments {
+		if segment.baseOffset <= off && off < segment.nextOffset {
+			s = segment
+			break
+		}
+	}
+	if s == nil {
+		return nil, fmt.Errorf("%w: %d", ErrOffsetOutOfRange, off)
+	}
+	return s.Read(off)
+}
+
+// Close stops retention and the sync timer, then closes every segment in
+// the log. It's safe to call more than once.
+func (l *Log) Close() error {
+	l.closeOnce.Do(func() {
+		if l.retentionDone != nil {
+			close(l.retentionDone)
+		}
+		if l.syncDone != nil {
+			close(l.syncDone)
+		}
+	})
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, segment := range l.segments {
+		if err := segment.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove closes the log and deletes its directory, including every
+// segment's store and index files.
+func (l *Log) Remove() error {
+	if err := l.Close(); err != nil {
+		return err
+	}
+	return os.RemoveAll(l.Dir)
+}
+
+// LowestOffset returns the offset of the oldest record the log still
+// retains.
+func (l *Log) LowestOffset() (uint64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.segments[0].baseOffset, nil
+}
+
+// HighestOffset returns the offset of the newest record appended to the
+// log.
+func (l *Log) HighestOffset() (uint64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	off := l.segments[len(l.segments)-1].nextOffset
+	if off == 0 {
+		return 0, nil
+	}
+	return off - 1, nil
+}
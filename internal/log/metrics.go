@@ -0,0 +1,24 @@
+package log
+
+// MetricsHook lets a caller observe storage and retention activity
+// without coupling the log package to any particular metrics library.
+// A Prometheus-backed implementation would export these as the gauge
+// storage_bytes_total and the counters size_retentions_total and
+// time_retentions_total.
+type MetricsHook interface {
+	// StorageBytes reports the log's total on-disk size, in bytes,
+	// after an append or a retention pass.
+	StorageBytes(total uint64)
+	// SizeRetention is called once per segment dropped because the
+	// log's total size exceeded Config.Retention.MaxBytes.
+	SizeRetention()
+	// TimeRetention is called once per segment dropped because its
+	// newest record was older than Config.Retention.MaxAge.
+	TimeRetention()
+}
+
+type noopMetricsHook struct{}
+
+func (noopMetricsHook) StorageBytes(uint64) {}
+func (noopMetricsHook) SizeRetention()      {}
+func (noopMetricsHook) TimeRetention()      {}
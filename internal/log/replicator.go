@@ -0,0 +1,185 @@
+package log
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	checkpointFile = ".replication-checkpoint"
+
+	initialReplicationBackoff = 100 * time.Millisecond
+	maxReplicationBackoff     = 30 * time.Second
+)
+
+// replicationRecord is the wire format streamed by a leader's
+// /topics/{name}/replicate endpoint: one JSON object per line, in
+// ascending offset order. Error is set instead of Value when the leader
+// can't serve Offset at all - e.g. it's fallen out of the leader's
+// retention window - so the follower can fail loudly rather than treat
+// the connection closing as just another clean disconnect to retry.
+type replicationRecord struct {
+	Offset uint64 `json:"offset"`
+	Value  []byte `json:"value,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// replicationGapError means the leader sent a record past what l has
+// applied, with nothing in between to fill the hole. Follow treats this
+// as terminal rather than silently skipping ahead.
+type replicationGapError struct {
+	have, want uint64
+}
+
+func (e *replicationGapError) Error() string {
+	return fmt.Sprintf("log: replication gap: have up to offset %d, leader sent %d", e.have, e.want)
+}
+
+// Follow makes l a replica of the log served at leaderURL: it opens a
+// long-lived stream to leaderURL, resuming just past whatever offset l
+// last checkpointed, and appends every record it receives, preserving
+// offsets. It backs off and reconnects on a dropped connection, and
+// blocks until ctx is done or a terminal error occurs - most notably a
+// gap between what l has and what the leader sent, which Follow refuses
+// to paper over. Run it in its own goroutine to replicate in the
+// background.
+func (l *Log) Follow(ctx context.Context, leaderURL string) error {
+	next, err := l.loadCheckpoint()
+	if err != nil {
+		return err
+	}
+
+	backoff := initialReplicationBackoff
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		applied, err := l.followOnce(ctx, leaderURL, next)
+		next = applied
+
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(*replicationGapError); ok {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+		if backoff > maxReplicationBackoff {
+			backoff = maxReplicationBackoff
+		}
+	}
+}
+
+// followOnce opens a single connection to leaderURL starting at from,
+// applies whatever it streams back, and returns the next offset to
+// resume at along with why the connection ended. A cleanly closed
+// stream is reported as io.EOF so Follow knows to reconnect rather than
+// treat it as success.
+func (l *Log) followOnce(ctx context.Context, leaderURL string, from uint64) (uint64, error) {
+	u, err := url.Parse(leaderURL)
+	if err != nil {
+		return from, err
+	}
+	q := u.Query()
+	q.Set("from", strconv.FormatUint(from, 10))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return from, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return from, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return from, fmt.Errorf("log: replicate request to %s failed: %s", leaderURL, resp.Status)
+	}
+
+	next := from
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var rec replicationRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return next, err
+		}
+
+		if rec.Error != "" {
+			return next, &replicationGapError{have: next, want: rec.Offset}
+		}
+
+		if rec.Offset < next {
+			// the leader can resend a little overlap right after we
+			// reconnect; we've already applied this one
+			continue
+		}
+		if rec.Offset > next {
+			return next, &replicationGapError{have: next, want: rec.Offset}
+		}
+
+		// AppendAt verifies rec.Offset against the log's own state
+		// before writing anything, so a record the leader resent past
+		// what our checkpoint (possibly stale, e.g. after a crash
+		// between this append and its checkpoint save) remembers can't
+		// land twice under a second, bogus offset.
+		if _, err := l.AppendAt(rec.Offset, rec.Value); err != nil {
+			return next, &replicationGapError{have: next, want: rec.Offset}
+		}
+
+		next = rec.Offset + 1
+		if err := l.saveCheckpoint(next); err != nil {
+			return next, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return next, err
+	}
+	return next, io.EOF
+}
+
+func (l *Log) loadCheckpoint() (uint64, error) {
+	b, err := os.ReadFile(filepath.Join(l.Dir, checkpointFile))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+func (l *Log) saveCheckpoint(next uint64) error {
+	path := filepath.Join(l.Dir, checkpointFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatUint(next, 10)), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,166 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogFollow(t *testing.T) {
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"offset":0,"value":"Zmlyc3Q="}`)
+		fmt.Fprintln(w, `{"offset":1,"value":"c2Vjb25k"}`)
+	}))
+	defer leader.Close()
+
+	dir, err := os.MkdirTemp("", "replicator_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := NewLog(dir, Config{})
+	require.NoError(t, err)
+	defer l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- l.Follow(ctx, leader.URL)
+	}()
+
+	require.Eventually(t, func() bool {
+		off, err := l.HighestOffset()
+		return err == nil && off == 1
+	}, time.Second, 10*time.Millisecond)
+
+	got, err := l.Read(0)
+	require.NoError(t, err)
+	require.Equal(t, []byte("first"), got)
+
+	got, err = l.Read(1)
+	require.NoError(t, err)
+	require.Equal(t, []byte("second"), got)
+
+	cancel()
+	select {
+	case err := <-errCh:
+		require.Equal(t, context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatal("Follow didn't return after ctx was canceled")
+	}
+}
+
+func TestLogFollowGap(t *testing.T) {
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"offset":5,"value":"b29wcw=="}`)
+	}))
+	defer leader.Close()
+
+	dir, err := os.MkdirTemp("", "replicator_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := NewLog(dir, Config{})
+	require.NoError(t, err)
+	defer l.Close()
+
+	err = l.Follow(context.Background(), leader.URL)
+	require.Error(t, err)
+	_, ok := err.(*replicationGapError)
+	require.True(t, ok)
+}
+
+// TestLogFollowStaleCheckpoint guards against Follow looping forever
+// and re-appending junk when the checkpoint on disk lags what's
+// actually in the log - e.g. after a crash between an applied append
+// and the checkpoint save meant to follow it - and the leader then
+// resends the offset the stale checkpoint asks for.
+func TestLogFollowStaleCheckpoint(t *testing.T) {
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"offset":0,"value":"Zmlyc3Q="}`)
+	}))
+	defer leader.Close()
+
+	dir, err := os.MkdirTemp("", "replicator_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := NewLog(dir, Config{})
+	require.NoError(t, err)
+	defer l.Close()
+
+	// simulate the crash window: the record is already applied, but the
+	// checkpoint was never saved, so Follow will ask for offset 0 again
+	_, err = l.Append([]byte("first"))
+	require.NoError(t, err)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- l.Follow(context.Background(), leader.URL) }()
+
+	select {
+	case err := <-errCh:
+		_, ok := err.(*replicationGapError)
+		require.True(t, ok, "want a terminal replicationGapError, got %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("Follow didn't return after the leader resent an already-applied offset")
+	}
+
+	off, err := l.HighestOffset()
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), off, "the resent record must not be appended a second time")
+}
+
+// TestLogFollowRemoteError guards against a leader signaling that it
+// can't serve an offset at all - e.g. because it's fallen out of
+// retention - getting treated as an ordinary clean disconnect and
+// retried forever instead of failing loudly like every other gap.
+func TestLogFollowRemoteError(t *testing.T) {
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"offset":0,"error":"offset out of range: 0"}`)
+	}))
+	defer leader.Close()
+
+	dir, err := os.MkdirTemp("", "replicator_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := NewLog(dir, Config{})
+	require.NoError(t, err)
+	defer l.Close()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- l.Follow(context.Background(), leader.URL) }()
+
+	select {
+	case err := <-errCh:
+		_, ok := err.(*replicationGapError)
+		require.True(t, ok, "want a terminal replicationGapError, got %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("Follow didn't return after the leader reported it couldn't serve the offset")
+	}
+}
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	dir, err := os.MkdirTemp("", "replicator_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := NewLog(dir, Config{})
+	require.NoError(t, err)
+	defer l.Close()
+
+	off, err := l.loadCheckpoint()
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), off)
+
+	require.NoError(t, l.saveCheckpoint(42))
+
+	off, err = l.loadCheckpoint()
+	require.NoError(t, err)
+	require.Equal(t, uint64(42), off)
+}
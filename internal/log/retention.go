@@ -0,0 +1,92 @@
+package log
+
+import "time"
+
+// startRetention launches the background goroutine that prunes segments
+// once the log's total on-disk size exceeds Config.Retention.MaxBytes or
+// a segment's newest record is older than Config.Retention.MaxAge. It's
+// a no-op if neither limit is configured.
+func (l *Log) startRetention() {
+	if l.Config.Retention.MaxBytes == 0 && l.Config.Retention.MaxAge == 0 {
+		return
+	}
+
+	interval := l.Config.Retention.CheckInterval
+	if interval == 0 {
+		interval = defaultRetentionCheckInterval
+	}
+
+	l.retentionDone = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				l.applyRetention()
+			case <-l.retentionDone:
+				return
+			}
+		}
+	}()
+}
+
+// applyRetention scans segments oldest-first, dropping whole segments
+// (never partial ones) until the log is back within its configured
+// bounds. The active segment is never a candidate. It takes l.mu only
+// long enough to swap out the dropped segments; the (comparatively slow)
+// file removal happens afterward, so readers mid-Read are never blocked
+// on it and any reader that already resolved its segment keeps working
+// until it's done.
+func (l *Log) applyRetention() {
+	l.mu.RLock()
+	segments := append([]*segment(nil), l.segments...)
+	l.mu.RUnlock()
+
+	if len(segments) <= 1 {
+		return
+	}
+	// the last segment is always the active one; it's never pruned
+	candidates := segments[:len(segments)-1]
+
+	var total uint64
+	for _, s := range segments {
+		total += s.store.size
+	}
+	l.Config.Metrics.StorageBytes(total)
+
+	remaining := total
+	var drop []*segment
+	for _, s := range candidates {
+		sizeExceeded := l.Config.Retention.MaxBytes > 0 && remaining > l.Config.Retention.MaxBytes
+		ageExceeded := l.Config.Retention.MaxAge > 0 && time.Since(s.newestAppend) > l.Config.Retention.MaxAge
+		if !sizeExceeded && !ageExceeded {
+			// segments are oldest-first and get no younger as we go, so
+			// once one is within bounds everything after it is too
+			break
+		}
+
+		drop = append(drop, s)
+		remaining -= s.store.size
+
+		if sizeExceeded {
+			l.Config.Metrics.SizeRetention()
+		} else {
+			l.Config.Metrics.TimeRetention()
+		}
+	}
+
+	if len(drop) == 0 {
+		return
+	}
+
+	l.mu.Lock()
+	l.segments = append([]*segment(nil), l.segments[len(drop):]...)
+	l.mu.Unlock()
+
+	for _, s := range drop {
+		// best effort: a failed removal here just means the file lingers
+		// on disk until the next pass tries again
+		_ = s.Remove()
+	}
+}
@@ -0,0 +1,45 @@
+package log
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMetricsHook struct {
+	sizeRetentions int
+}
+
+func (h *recordingMetricsHook) StorageBytes(uint64) {}
+func (h *recordingMetricsHook) SizeRetention()      { h.sizeRetentions++ }
+func (h *recordingMetricsHook) TimeRetention()      {}
+
+func TestLogSizeRetention(t *testing.T) {
+	dir, err := os.MkdirTemp("", "retention_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	hook := &recordingMetricsHook{}
+	c := Config{}
+	c.Segment.MaxStoreBytes = 32
+	c.Retention.MaxBytes = 1
+	c.Retention.CheckInterval = time.Hour // drive pruning manually below
+	c.Metrics = hook
+
+	l, err := NewLog(dir, c)
+	require.NoError(t, err)
+	defer l.Close()
+
+	for i := 0; i < 6; i++ {
+		_, err := l.Append([]byte("hello world"))
+		require.NoError(t, err)
+	}
+	require.Greater(t, len(l.segments), 1)
+
+	l.applyRetention()
+
+	require.Equal(t, 1, len(l.segments))
+	require.Greater(t, hook.sizeRetentions, 0)
+}
@@ -0,0 +1,165 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// segment pairs a store file with its index file, together covering a
+// contiguous range of offsets starting at baseOffset. A Log is made up of
+// many segments; only the newest one is ever written to.
+type segment struct {
+	store      *store
+	index      *index
+	baseOffset uint64
+	nextOffset uint64
+	config     Config
+
+	// newestAppend is when the segment last accepted a record; it's how
+	// retention judges a segment's age without storing a timestamp per
+	// record.
+	newestAppend time.Time
+}
+
+// newSegment opens (or creates) the store and index files for baseOffset
+// inside dir, naming them "<baseOffset>.store" and "<baseOffset>.index".
+func newSegment(dir string, baseOffset uint64, c Config) (*segment, error) {
+	s := &segment{
+		baseOffset: baseOffset,
+		config:     c,
+		// we don't persist a timestamp per record, so treat a segment as
+		// fresh as of when we open it; Append keeps this current from
+		// then on
+		newestAppend: time.Now(),
+	}
+
+	storeFile, err := os.OpenFile(
+		filepath.Join(dir, fmt.Sprintf("%d%s", baseOffset, ".store")),
+		os.O_RDWR|os.O_CREATE|os.O_APPEND,
+		0644,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if s.store, err = newStore(storeFile); err != nil {
+		return nil, err
+	}
+
+	indexFile, err := os.OpenFile(
+		filepath.Join(dir, fmt.Sprintf("%d%s", baseOffset, ".index")),
+		os.O_RDWR|os.O_CREATE,
+		0644,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if s.index, err = newIndex(indexFile, c); err != nil {
+		return nil, err
+	}
+
+	// the index's last entry, if any, tells us what offset to resume
+	// writing at when we're recreating a segment from an existing file
+	if off, _, err := s.index.Read(-1); err != nil {
+		s.nextOffset = baseOffset
+	} else {
+		s.nextOffset = baseOffset + uint64(off) + 1
+	}
+
+	return s, nil
+}
+
+// Append writes p to the segment's store and records its position in the
+// segment's index, returning the absolute offset assigned to it.
+func (s *segment) Append(p []byte) (offset uint64, err error) {
+	cur := s.nextOffset
+	_, pos, err := s.store.Append(p)
+	if err != nil {
+		return 0, err
+	}
+	if err = s.index.Write(
+		// offsets in the index are relative to the segment's baseOffset
+		uint32(s.nextOffset-s.baseOffset),
+		pos,
+	); err != nil {
+		return 0, err
+	}
+	s.nextOffset++
+	s.newestAppend = time.Now()
+	return cur, nil
+}
+
+// AppendBatch writes every record in ps to the segment's store in a
+// single call and records all of their positions in the index in a
+// single call, returning the absolute offsets assigned to them in order.
+func (s *segment) AppendBatch(ps [][]byte) ([]uint64, error) {
+	offsets := make([]uint64, len(ps))
+	relOffs := make([]uint32, len(ps))
+	for i := range ps {
+		offsets[i] = s.nextOffset + uint64(i)
+		relOffs[i] = uint32(offsets[i] - s.baseOffset)
+	}
+
+	_, positions, err := s.store.AppendBatch(ps)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.index.WriteBatch(relOffs, positions); err != nil {
+		return nil, err
+	}
+
+	s.nextOffset += uint64(len(ps))
+	s.newestAppend = time.Now()
+	return offsets, nil
+}
+
+// Sync flushes the segment's store and index to stable storage.
+func (s *segment) Sync() error {
+	if err := s.store.Sync(); err != nil {
+		return err
+	}
+	return s.index.Sync()
+}
+
+// Read returns the record stored at the given absolute offset.
+func (s *segment) Read(off uint64) ([]byte, error) {
+	_, pos, err := s.index.Read(int64(off - s.baseOffset))
+	if err != nil {
+		return nil, err
+	}
+	return s.store.Read(pos)
+}
+
+// IsMaxed reports whether the segment has grown past either configured
+// limit and should stop accepting writes.
+func (s *segment) IsMaxed() bool {
+	return s.store.size >= s.config.Segment.MaxStoreBytes ||
+		s.index.size >= s.config.Segment.MaxIndexBytes
+}
+
+// Close flushes and closes the segment's store and index files.
+func (s *segment) Close() error {
+	if err := s.index.Close(); err != nil {
+		return err
+	}
+	if err := s.store.Close(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Remove closes the segment and deletes its store and index files from
+// disk.
+func (s *segment) Remove() error {
+	if err := s.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(s.store.Name()); err != nil {
+		return err
+	}
+	if err := os.Remove(s.index.Name()); err != nil {
+		return err
+	}
+	return nil
+}
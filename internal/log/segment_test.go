@@ -0,0 +1,52 @@
+package log
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const entriesPerSegment = 3
+
+func TestSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "segment_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	want := []byte("hello world")
+
+	c := Config{}
+	c.Segment.MaxStoreBytes = 1024
+	c.Segment.MaxIndexBytes = entriesPerSegment * totWidth
+
+	s, err := newSegment(dir, 16, c)
+	require.NoError(t, err)
+	require.Equal(t, uint64(16), s.nextOffset)
+	require.False(t, s.IsMaxed())
+
+	for i := uint64(0); i < entriesPerSegment; i++ {
+		off, err := s.Append(want)
+		require.NoError(t, err)
+		require.Equal(t, 16+i, off)
+
+		got, err := s.Read(off)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+
+	require.True(t, s.IsMaxed())
+
+	require.NoError(t, s.Close())
+
+	// should pick up where it left off when recreated from the existing
+	// store and index files
+	s, err = newSegment(dir, 16, c)
+	require.NoError(t, err)
+	require.True(t, s.IsMaxed())
+
+	require.NoError(t, s.Remove())
+	s, err = newSegment(dir, 16, c)
+	require.NoError(t, err)
+	require.False(t, s.IsMaxed())
+}
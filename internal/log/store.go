@@ -2,6 +2,7 @@ package log
 
 import (
 	"bufio"
+	"context"
 	"encoding/binary"
 	"os"
 	"sync"
@@ -28,6 +29,8 @@ type store struct {
 	// the file, we can push it all together from the buffer to reduce # of system calls
 	buf  *bufio.Writer
 	size uint64
+	// cond wakes up any LiveReader blocked in Wait whenever size grows
+	cond *sync.Cond
 }
 
 // creates a new store for the given file
@@ -38,13 +41,14 @@ func newStore(f *os.File) (*store, error) {
 		return nil, err
 	}
 
-	size := uint64(file.Size())
-	return &store{
+	s := &store{
 		File: f,
-		size: size,
+		size: uint64(file.Size()),
 		// when flushing, writes to f
 		buf: bufio.NewWriter(f),
-	}, nil
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s, nil
 }
 
 func (s *store) Append(p []byte) (n uint64, pos uint64, err error) {
@@ -65,10 +69,84 @@ func (s *store) Append(p []byte) (n uint64, pos uint64, err error) {
 
 	w += lenWidth
 	s.size += uint64(w)
+	// wake up any LiveReader waiting on the store to grow past its
+	// current read position
+	s.cond.Broadcast()
 	// return bytes written
 	return uint64(w), pos, nil
 }
 
+// AppendBatch writes every record in ps as a single bufio.Writer write,
+// the same way Append writes one, and returns each record's byte count
+// and store position in the order given.
+func (s *store) AppendBatch(ps [][]byte) (ns []uint64, positions []uint64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ns = make([]uint64, len(ps))
+	positions = make([]uint64, len(ps))
+
+	for i, p := range ps {
+		positions[i] = s.size
+
+		if err := binary.Write(s.buf, enc, uint64(len(p))); err != nil {
+			return nil, nil, err
+		}
+		w, err := s.buf.Write(p)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		w += lenWidth
+		s.size += uint64(w)
+		ns[i] = uint64(w)
+	}
+
+	s.cond.Broadcast()
+	return ns, positions, nil
+}
+
+// Sync flushes buffered writes to the underlying file and flushes that
+// file to stable storage.
+func (s *store) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.buf.Flush(); err != nil {
+		return err
+	}
+	return s.File.Sync()
+}
+
+// Wait blocks until the store has grown to at least minSize bytes or ctx
+// is done, whichever comes first.
+func (s *store) Wait(ctx context.Context, minSize uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.size < minSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// sync.Cond has no context-aware wait, so race an ordinary
+		// Wait against a goroutine that broadcasts when ctx is done
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				s.mu.Lock()
+				s.cond.Broadcast()
+				s.mu.Unlock()
+			case <-done:
+			}
+		}()
+		s.cond.Wait()
+		close(done)
+	}
+	return nil
+}
+
 // essentially returns record stored at a given position in the file
 func (s *store) Read(pos uint64) ([]byte, error) {
 	s.mu.Lock()
@@ -0,0 +1,33 @@
+package log
+
+import "time"
+
+// SyncMode controls when a Log flushes its active segment's store and
+// index to stable storage.
+type SyncMode int
+
+const (
+	// SyncNever never syncs on its own; data reaches disk whenever the
+	// OS happens to flush it, or when the segment is closed. This is
+	// the zero value, so a Config left unset keeps the old behavior.
+	SyncNever SyncMode = iota
+	// SyncAlways syncs after every Append and AppendBatch call. Safest,
+	// and slowest: per-write fsyncs dominate throughput for append-only
+	// workloads.
+	SyncAlways
+	// SyncBatch syncs once per AppendBatch call, but not after a plain
+	// Append. Groups fsyncs at batch boundaries instead of per record.
+	SyncBatch
+	// SyncInterval syncs the active segment on a fixed timer instead of
+	// tying it to any particular write.
+	SyncInterval
+)
+
+// SyncConfig is Config.Sync: the policy controlling how often a Log's
+// active segment is flushed to stable storage.
+type SyncConfig struct {
+	Mode SyncMode
+	// Interval is how often to sync when Mode is SyncInterval. Defaults
+	// to one second if left zero.
+	Interval time.Duration
+}
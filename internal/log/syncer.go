@@ -0,0 +1,41 @@
+package log
+
+import "time"
+
+// defaultSyncInterval is used for SyncInterval mode when
+// Config.Sync.Interval is left zero.
+const defaultSyncInterval = time.Second
+
+// startSync launches the background goroutine that flushes the active
+// segment to stable storage on a fixed interval. It only applies to
+// Config.Sync.Mode == SyncInterval; the other modes sync inline with
+// Append/AppendBatch (or never, for SyncNever).
+func (l *Log) startSync() {
+	if l.Config.Sync.Mode != SyncInterval {
+		return
+	}
+
+	interval := l.Config.Sync.Interval
+	if interval == 0 {
+		interval = defaultSyncInterval
+	}
+
+	l.syncDone = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				l.mu.RLock()
+				s := l.activeSegment
+				l.mu.RUnlock()
+				// best effort: a failed periodic sync just means we try
+				// again next tick
+				_ = s.Sync()
+			case <-l.syncDone:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,431 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/thomhuang/proglog/internal/log"
+)
+
+// dataDir is where topics' on-disk segments live, relative to the
+// server's working directory.
+const dataDir = "data"
+
+// NewHTTPServer wires up the JSON-over-HTTP log API: topics are created,
+// listed, and deleted under /topics, and records are produced and
+// consumed under /topics/{name}/records. c is applied to every topic the
+// server opens, so it's the only way to turn on the KV overlay,
+// retention, or a non-default fsync policy from the server's API surface.
+func NewHTTPServer(addr string, c log.Config) (*http.Server, error) {
+	httpsrv, err := newHTTPServer(c)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Server{
+		Addr:    addr,
+		Handler: httpsrv.mux(),
+	}, nil
+}
+
+type httpServer struct {
+	Log *Log
+}
+
+func newHTTPServer(c log.Config) (*httpServer, error) {
+	l, err := NewLog(dataDir, c)
+	if err != nil {
+		return nil, err
+	}
+	return &httpServer{Log: l}, nil
+}
+
+// mux wires up the routes served by s. Split out from NewHTTPServer so
+// tests can serve an httpServer (backed by a temp dir, not dataDir)
+// through httptest.NewServer without going through a real net.Listener.
+func (s *httpServer) mux() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/topics", s.handleTopics)
+	mux.HandleFunc("/topics/", s.handleTopic)
+	return mux
+}
+
+type ListTopicsResponse struct {
+	Topics []string `json:"topics"`
+}
+
+func (s *httpServer) handleTopics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	res := ListTopicsResponse{Topics: s.Log.Topics()}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleTopic dispatches requests under /topics/{name} to topic CRUD, or,
+// if the path carries a trailing /records segment, to the produce and
+// consume handlers for that topic.
+func (s *httpServer) handleTopic(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/topics/")
+	name, rest, hasRest := strings.Cut(path, "/")
+	if name == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if hasRest {
+		switch {
+		case rest == "records":
+			s.handleRecords(w, r, name)
+		case rest == "records:batch":
+			s.handleProduceBatch(w, r, name)
+		case rest == "records/live":
+			s.handleLive(w, r, name)
+		case rest == "keys":
+			s.handleKeys(w, r, name)
+		case strings.HasPrefix(rest, "keys/"):
+			s.handleKey(w, r, name, strings.TrimPrefix(rest, "keys/"))
+		case rest == "replicate":
+			s.handleReplicate(w, r, name)
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		s.createTopic(w, r, name)
+	case http.MethodDelete:
+		s.deleteTopic(w, r, name)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *httpServer) createTopic(w http.ResponseWriter, r *http.Request, name string) {
+	if err := s.Log.CreateTopic(name); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *httpServer) deleteTopic(w http.ResponseWriter, r *http.Request, name string) {
+	if err := s.Log.DeleteTopic(name); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type ProduceRequest struct {
+	Record Record `json:"record"`
+}
+
+type ProduceResponse struct {
+	Offset uint64 `json:"offset"`
+}
+
+type ConsumeRequest struct {
+	Offset uint64 `json:"offset"`
+}
+
+type ConsumeResponse struct {
+	Record Record `json:"record"`
+}
+
+func (s *httpServer) handleRecords(w http.ResponseWriter, r *http.Request, topic string) {
+	switch r.Method {
+	case http.MethodPost:
+		s.handleProduce(w, r, topic)
+	case http.MethodGet:
+		s.handleConsume(w, r, topic)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *httpServer) handleProduce(w http.ResponseWriter, r *http.Request, topic string) {
+	var req ProduceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	off, err := s.Log.Append(topic, req.Record)
+	if errors.Is(err, ErrTopicNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err == ErrKeyRequired || errors.Is(err, log.ErrKeyTooLong) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(ProduceResponse{Offset: off}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *httpServer) handleConsume(w http.ResponseWriter, r *http.Request, topic string) {
+	var req ConsumeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	record, err := s.Log.Read(topic, req.Offset)
+	if errors.Is(err, ErrTopicNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err == ErrOffsetNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(ConsumeResponse{Record: record}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type ProduceBatchRequest struct {
+	Records []Record `json:"records"`
+}
+
+type ProduceBatchResponse struct {
+	Offsets []uint64 `json:"offsets"`
+}
+
+// handleProduceBatch appends every record in the request in one call,
+// so their fsyncs (if any, per Config.Sync) are grouped at the batch
+// boundary instead of paid once per record.
+func (s *httpServer) handleProduceBatch(w http.ResponseWriter, r *http.Request, topic string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ProduceBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	offsets, err := s.Log.AppendBatch(topic, req.Records)
+	if err == ErrKVBatchUnsupported {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(ProduceBatchResponse{Offsets: offsets}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type ListKeysResponse struct {
+	Keys []string `json:"keys"`
+}
+
+// handleKeys lists every live key in topic, optionally filtered to a
+// ?prefix= query parameter.
+func (s *httpServer) handleKeys(w http.ResponseWriter, r *http.Request, topic string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	keys, err := s.Log.Keys(topic, []byte(r.URL.Query().Get("prefix")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	strs := make([]string, len(keys))
+	for i, k := range keys {
+		strs[i] = string(k)
+	}
+	if err := json.NewEncoder(w).Encode(ListKeysResponse{Keys: strs}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleKey gets or deletes a single keyed record in topic.
+func (s *httpServer) handleKey(w http.ResponseWriter, r *http.Request, topic, key string) {
+	switch r.Method {
+	case http.MethodGet:
+		value, err := s.Log.GetKey(topic, []byte(key))
+		if errors.Is(err, ErrTopicNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err == log.ErrKeyNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(Record{Value: value, Key: []byte(key)}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	case http.MethodDelete:
+		err := s.Log.DeleteKey(topic, []byte(key))
+		if errors.Is(err, ErrTopicNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, log.ErrKeyTooLong) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleLive streams topic's records to the client as server-sent
+// events, starting at the "from" query offset and following the topic
+// as it grows until the client disconnects.
+func (s *httpServer) handleLive(w http.ResponseWriter, r *http.Request, topic string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	topicLog, err := s.Log.topicLog(topic)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	from, _ := strconv.ParseUint(r.URL.Query().Get("from"), 10, 64)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx := r.Context()
+	live := topicLog.NewLiveReader(from)
+	for off := from; ; off++ {
+		value, err := live.Next(ctx)
+		if err != nil {
+			return
+		}
+
+		payload, err := json.Marshal(Record{Value: value, Offset: off})
+		if err != nil {
+			return
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", payload); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// handleReplicate serves topic as a leader feed for Log.Follow: newline-
+// delimited JSON records, one per line, starting at the "from" query
+// offset and continuing as the topic grows until the client disconnects.
+func (s *httpServer) handleReplicate(w http.ResponseWriter, r *http.Request, topic string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	topicLog, err := s.Log.topicLog(topic)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	from, _ := strconv.ParseUint(r.URL.Query().Get("from"), 10, 64)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx := r.Context()
+	live := topicLog.NewLiveReader(from)
+	for off := from; ; off++ {
+		value, err := live.Next(ctx)
+		if err != nil {
+			// if off has fallen out of retention, say so on the wire
+			// instead of just closing the connection - Log.Follow can't
+			// tell that apart from an ordinary disconnect to retry after
+			if errors.Is(err, log.ErrOffsetOutOfRange) {
+				s.writeReplicateError(w, flusher, off, err)
+			}
+			return
+		}
+
+		payload, err := json.Marshal(Record{Value: value, Offset: off})
+		if err != nil {
+			return
+		}
+		if _, err := w.Write(append(payload, '\n')); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// replicateErrorLine is the wire format handleReplicate writes in place
+// of a record when it can't serve the requested offset at all, so
+// Log.Follow can tell that apart from the connection just closing.
+type replicateErrorLine struct {
+	Offset uint64 `json:"offset"`
+	Error  string `json:"error"`
+}
+
+func (s *httpServer) writeReplicateError(w http.ResponseWriter, flusher http.Flusher, off uint64, err error) {
+	payload, merr := json.Marshal(replicateErrorLine{Offset: off, Error: err.Error()})
+	if merr != nil {
+		return
+	}
+	if _, err := w.Write(append(payload, '\n')); err != nil {
+		return
+	}
+	flusher.Flush()
+}
@@ -0,0 +1,289 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thomhuang/proglog/internal/log"
+)
+
+// newTestServer builds an httpServer backed by a temp directory (not
+// dataDir, which is only ever used by the real NewHTTPServer) and serves
+// it over httptest.NewServer.
+func newTestServer(t *testing.T, c log.Config) *httptest.Server {
+	l, err := NewLog(t.TempDir(), c)
+	require.NoError(t, err)
+
+	hs := &httpServer{Log: l}
+	ts := httptest.NewServer(hs.mux())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func doJSON(t *testing.T, method, url string, body, out interface{}) *http.Response {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	if out != nil && resp.StatusCode < 300 {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp
+}
+
+func TestHTTPTopicLifecycle(t *testing.T) {
+	ts := newTestServer(t, log.Config{})
+
+	resp := doJSON(t, http.MethodPut, ts.URL+"/topics/orders", nil, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// creating the same topic twice is a conflict
+	resp = doJSON(t, http.MethodPut, ts.URL+"/topics/orders", nil, nil)
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	var list ListTopicsResponse
+	resp = doJSON(t, http.MethodGet, ts.URL+"/topics", nil, &list)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []string{"orders"}, list.Topics)
+
+	resp = doJSON(t, http.MethodDelete, ts.URL+"/topics/orders", nil, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp = doJSON(t, http.MethodGet, ts.URL+"/topics", nil, &list)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, list.Topics)
+}
+
+func TestHTTPProduceConsume(t *testing.T) {
+	ts := newTestServer(t, log.Config{})
+
+	resp := doJSON(t, http.MethodPut, ts.URL+"/topics/orders", nil, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var produced ProduceResponse
+	resp = doJSON(t, http.MethodPost, ts.URL+"/topics/orders/records",
+		ProduceRequest{Record: Record{Value: []byte("hello")}}, &produced)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, uint64(0), produced.Offset)
+
+	var consumed ConsumeResponse
+	resp = doJSON(t, http.MethodGet, ts.URL+"/topics/orders/records",
+		ConsumeRequest{Offset: 0}, &consumed)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "hello", string(consumed.Record.Value))
+
+	// consuming an offset past the tip is a 404, not a 500
+	resp = doJSON(t, http.MethodGet, ts.URL+"/topics/orders/records",
+		ConsumeRequest{Offset: 99}, nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// a topic that doesn't exist at all is a client error too, not a 500
+	resp = doJSON(t, http.MethodGet, ts.URL+"/topics/missing/records",
+		ConsumeRequest{Offset: 0}, nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// TestHTTPMissingTopicIs404 guards against a missing topic falling
+// through to a 500 in the produce and keyed-lookup handlers, the same
+// way TestHTTPProduceConsume already guards the consume path.
+func TestHTTPMissingTopicIs404(t *testing.T) {
+	c := log.Config{}
+	c.KV.Enabled = true
+	ts := newTestServer(t, c)
+
+	resp := doJSON(t, http.MethodPost, ts.URL+"/topics/missing/records",
+		ProduceRequest{Record: Record{Key: []byte("k"), Value: []byte("v")}}, nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = doJSON(t, http.MethodGet, ts.URL+"/topics/missing/keys/k", nil, nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = doJSON(t, http.MethodDelete, ts.URL+"/topics/missing/keys/k", nil, nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestHTTPProduceBatch(t *testing.T) {
+	ts := newTestServer(t, log.Config{})
+
+	resp := doJSON(t, http.MethodPut, ts.URL+"/topics/orders", nil, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var batched ProduceBatchResponse
+	resp = doJSON(t, http.MethodPost, ts.URL+"/topics/orders/records:batch",
+		ProduceBatchRequest{Records: []Record{
+			{Value: []byte("one")},
+			{Value: []byte("two")},
+		}}, &batched)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []uint64{0, 1}, batched.Offsets)
+
+	// an empty batch shouldn't panic or error
+	resp = doJSON(t, http.MethodPost, ts.URL+"/topics/orders/records:batch",
+		ProduceBatchRequest{}, &batched)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, batched.Offsets)
+}
+
+func TestHTTPKeys(t *testing.T) {
+	c := log.Config{}
+	c.KV.Enabled = true
+	ts := newTestServer(t, c)
+
+	resp := doJSON(t, http.MethodPut, ts.URL+"/topics/users", nil, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = doJSON(t, http.MethodPost, ts.URL+"/topics/users/records",
+		ProduceRequest{Record: Record{Key: []byte("alice"), Value: []byte("1")}}, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var got Record
+	resp = doJSON(t, http.MethodGet, ts.URL+"/topics/users/keys/alice", nil, &got)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "1", string(got.Value))
+
+	var keys ListKeysResponse
+	resp = doJSON(t, http.MethodGet, ts.URL+"/topics/users/keys", nil, &keys)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []string{"alice"}, keys.Keys)
+
+	resp = doJSON(t, http.MethodDelete, ts.URL+"/topics/users/keys/alice", nil, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp = doJSON(t, http.MethodGet, ts.URL+"/topics/users/keys/alice", nil, nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// TestHTTPKVRejectsKeylessProduce guards against a keyless or batched
+// produce against a KV-enabled topic silently falling through to the
+// plain (non-KV) append path, which the KV overlay's on-disk format
+// can't tolerate - see log.Config's KV field.
+func TestHTTPKVRejectsKeylessProduce(t *testing.T) {
+	c := log.Config{}
+	c.KV.Enabled = true
+	ts := newTestServer(t, c)
+
+	resp := doJSON(t, http.MethodPut, ts.URL+"/topics/users", nil, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = doJSON(t, http.MethodPost, ts.URL+"/topics/users/records",
+		ProduceRequest{Record: Record{Value: []byte("no key")}}, nil)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = doJSON(t, http.MethodPost, ts.URL+"/topics/users/records:batch",
+		ProduceBatchRequest{Records: []Record{{Key: []byte("a"), Value: []byte("1")}}}, nil)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestHTTPLive(t *testing.T) {
+	ts := newTestServer(t, log.Config{})
+
+	resp := doJSON(t, http.MethodPut, ts.URL+"/topics/orders", nil, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/topics/orders/records/live", nil)
+	require.NoError(t, err)
+
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	doJSON(t, http.MethodPost, ts.URL+"/topics/orders/records",
+		ProduceRequest{Record: Record{Value: []byte("hello")}}, nil)
+
+	scanner := bufio.NewScanner(resp.Body)
+	require.True(t, scanner.Scan())
+	require.Contains(t, scanner.Text(), `"value":"aGVsbG8="`)
+}
+
+// TestHTTPReplicatePrunedOffset guards against a follower asking for an
+// offset retention has already pruned off the leader and getting a
+// connection that just closes - indistinguishable from an ordinary
+// disconnect - instead of a signal Log.Follow can fail loudly on.
+func TestHTTPReplicatePrunedOffset(t *testing.T) {
+	c := log.Config{}
+	c.Segment.MaxStoreBytes = 32
+	c.Retention.MaxBytes = 1
+	c.Retention.CheckInterval = 10 * time.Millisecond
+	ts := newTestServer(t, c)
+
+	resp := doJSON(t, http.MethodPut, ts.URL+"/topics/orders", nil, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	for i := 0; i < 6; i++ {
+		doJSON(t, http.MethodPost, ts.URL+"/topics/orders/records",
+			ProduceRequest{Record: Record{Value: []byte("hello world")}}, nil)
+	}
+
+	require.Eventually(t, func() bool {
+		resp := doJSON(t, http.MethodGet, ts.URL+"/topics/orders/records",
+			ConsumeRequest{Offset: 0}, nil)
+		return resp.StatusCode == http.StatusNotFound
+	}, time.Second, 10*time.Millisecond, "offset 0 should eventually be pruned by retention")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/topics/orders/replicate?from=0", nil)
+	require.NoError(t, err)
+
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	scanner := bufio.NewScanner(resp.Body)
+	require.True(t, scanner.Scan())
+	require.Contains(t, scanner.Text(), `"error"`)
+}
+
+func TestHTTPReplicate(t *testing.T) {
+	ts := newTestServer(t, log.Config{})
+
+	resp := doJSON(t, http.MethodPut, ts.URL+"/topics/orders", nil, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	doJSON(t, http.MethodPost, ts.URL+"/topics/orders/records",
+		ProduceRequest{Record: Record{Value: []byte("hello")}}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/topics/orders/replicate?from=0", nil)
+	require.NoError(t, err)
+
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	scanner := bufio.NewScanner(resp.Body)
+	require.True(t, scanner.Scan())
+	require.Contains(t, scanner.Text(), `"value":"aGVsbG8="`)
+}
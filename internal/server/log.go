@@ -1,48 +1,224 @@
 package server
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
+
+	"github.com/thomhuang/proglog/internal/log"
 )
 
+// Log is a registry of per-topic logs, each rooted in its own
+// subdirectory of Dir so that one topic's segments can never collide
+// with another's. Every topic keeps its own independent offset space.
 type Log struct {
-	// have a mutex to ensure proper ordering of our records
 	mu sync.Mutex
-	// sequential list of our logs
-	records []Record
-}
-
-func NewLog() *Log {
-	return &Log{}
-}
-func (c *Log) Append(record Record) (uint64, error) {
-	// lock our resource
-	c.mu.Lock()
-	// defers unlocking of mutex until the function returns
-	defer c.mu.Unlock()
-	// set the record's offset in accordance to our stored logs
-	record.Offset = uint64(len(c.records))
-	// add the record to our stored logs
-	c.records = append(c.records, record)
-	return record.Offset, nil
-}
-
-func (c *Log) Read(offset uint64) (Record, error) {
-	// lock our resource
-	c.mu.Lock()
-	// defers unlocking of mutex until the function returns
-	defer c.mu.Unlock()
-	// if the log we're looking for is outside of our true range, err out
-	if offset >= uint64(len(c.records)) {
+
+	Dir    string
+	Config log.Config
+
+	topics map[string]*log.Log
+}
+
+// NewLog opens the topic registry rooted at dir. c is the log.Config
+// applied to every topic, new or reopened - e.g. whether the KV overlay
+// is enabled, retention limits, and the fsync policy.
+func NewLog(dir string, c log.Config) (*Log, error) {
+	l := &Log{
+		Dir:    dir,
+		Config: c,
+		topics: make(map[string]*log.Log),
+	}
+	if err := l.setup(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// setup reopens every topic subdirectory already on disk under Dir, so
+// that topics created by an earlier run are still reachable after a
+// restart instead of being orphaned on disk.
+func (l *Log) setup() error {
+	entries, err := os.ReadDir(l.Dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		topicLog, err := log.NewLog(filepath.Join(l.Dir, entry.Name()), l.Config)
+		if err != nil {
+			return err
+		}
+		l.topics[entry.Name()] = topicLog
+	}
+	return nil
+}
+
+// CreateTopic creates a new topic backed by its own on-disk log.
+func (l *Log) CreateTopic(name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.topics[name]; ok {
+		return ErrTopicExists
+	}
+
+	dir := filepath.Join(l.Dir, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	topicLog, err := log.NewLog(dir, l.Config)
+	if err != nil {
+		return err
+	}
+	l.topics[name] = topicLog
+	return nil
+}
+
+// Topics lists the names of every topic currently known to the log.
+func (l *Log) Topics() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	names := make([]string, 0, len(l.topics))
+	for name := range l.topics {
+		names = append(names, name)
+	}
+	return names
+}
+
+// DeleteTopic removes a topic along with all of its on-disk segments.
+func (l *Log) DeleteTopic(name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	topicLog, ok := l.topics[name]
+	if !ok {
+		return ErrTopicNotFound
+	}
+	delete(l.topics, name)
+	return topicLog.Remove()
+}
+
+// Append routes record to topic's log, assigning it an offset in that
+// topic's own offset space. If the KV overlay is enabled, record must
+// carry a key - log.Config documents that every record appended to a
+// KV-enabled log has to go through AppendKV, since the overlay can't
+// tell a keyed record apart from an arbitrary byte slice, so a keyless
+// produce against such a topic is rejected rather than silently falling
+// back to the plain append.
+func (l *Log) Append(topic string, record Record) (uint64, error) {
+	topicLog, err := l.topicLog(topic)
+	if err != nil {
+		return 0, err
+	}
+
+	if l.Config.KV.Enabled {
+		if len(record.Key) == 0 {
+			return 0, ErrKeyRequired
+		}
+		return topicLog.AppendKV(record.Key, record.Value)
+	}
+	return topicLog.Append(record.Value)
+}
+
+// AppendBatch routes every record in records to topic's log in a single
+// call, returning the offsets assigned to them in order. There's no
+// batched counterpart to AppendKV, so this is rejected against a
+// KV-enabled topic rather than appending records the KV overlay would
+// have no way to index.
+func (l *Log) AppendBatch(topic string, records []Record) ([]uint64, error) {
+	topicLog, err := l.topicLog(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.Config.KV.Enabled {
+		return nil, ErrKVBatchUnsupported
+	}
+
+	values := make([][]byte, len(records))
+	for i, record := range records {
+		values[i] = record.Value
+	}
+	return topicLog.AppendBatch(values)
+}
+
+// Read returns the record stored at offset within topic.
+func (l *Log) Read(topic string, offset uint64) (Record, error) {
+	topicLog, err := l.topicLog(topic)
+	if err != nil {
+		return Record{}, err
+	}
+
+	value, err := topicLog.Read(offset)
+	if errors.Is(err, log.ErrOffsetOutOfRange) {
 		return Record{}, ErrOffsetNotFound
 	}
-	//return record at given offset
-	return c.records[offset], nil
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{Value: value, Offset: offset}, nil
+}
+
+// GetKey returns the live value stored for key within topic.
+func (l *Log) GetKey(topic string, key []byte) ([]byte, error) {
+	topicLog, err := l.topicLog(topic)
+	if err != nil {
+		return nil, err
+	}
+	return topicLog.Get(key)
+}
+
+// DeleteKey removes key from topic.
+func (l *Log) DeleteKey(topic string, key []byte) error {
+	topicLog, err := l.topicLog(topic)
+	if err != nil {
+		return err
+	}
+	return topicLog.Delete(key)
+}
+
+// Keys returns every live key in topic with the given prefix.
+func (l *Log) Keys(topic string, prefix []byte) ([][]byte, error) {
+	topicLog, err := l.topicLog(topic)
+	if err != nil {
+		return nil, err
+	}
+	return topicLog.Keys(prefix), nil
+}
+
+func (l *Log) topicLog(topic string) (*log.Log, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	topicLog, ok := l.topics[topic]
+	if !ok {
+		return nil, ErrTopicNotFound
+	}
+	return topicLog, nil
 }
 
 type Record struct {
 	Value  []byte `json:"value"`
 	Offset uint64 `json:"offset"`
+	// Key is optional; set it to address the record by key instead of
+	// just offset, via Log.GetKey/DeleteKey (requires Config.KV.Enabled).
+	Key []byte `json:"key,omitempty"`
 }
 
 var ErrOffsetNotFound = fmt.Errorf("offset not found")
+var ErrTopicNotFound = fmt.Errorf("topic not found")
+var ErrTopicExists = fmt.Errorf("topic already exists")
+var ErrKeyRequired = fmt.Errorf("key required: topic has the KV overlay enabled")
+var ErrKVBatchUnsupported = fmt.Errorf("batch produce isn't supported on a topic with the KV overlay enabled")